@@ -0,0 +1,19 @@
+package main
+
+import "fmt"
+
+// normalizeCompression validates codec against the names the Sink drivers
+// support and returns it with an empty string normalized to "none". Each
+// driver maps the result onto its own client's native compression setting
+// rather than compressing payloads in application code, so messages stay
+// decodable by any standard Kafka consumer.
+func normalizeCompression(codec string) (string, error) {
+	switch codec {
+	case "":
+		return "none", nil
+	case "none", "gzip", "snappy", "lz4", "zstd":
+		return codec, nil
+	default:
+		return "", fmt.Errorf("unknown compression codec %q", codec)
+	}
+}
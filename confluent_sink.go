@@ -0,0 +1,154 @@
+//go:build confluent
+
+package main
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/confluentinc/confluent-kafka-go/kafka"
+)
+
+// ConfluentSink publishes records using confluent-kafka-go, the
+// cgo/librdkafka-backed client this tool originally shipped with.
+type ConfluentSink struct {
+	producer *kafka.Producer
+	topic    string
+}
+
+// NewConfluentSink creates a Sink backed by confluent-kafka-go.
+func NewConfluentSink(config *KafkaConfig) (*ConfluentSink, error) {
+	producerConfig := &kafka.ConfigMap{
+		"bootstrap.servers": config.Broker,
+		"client.id":         config.ClientID,
+		"retries":           config.MaxRetry,
+		"retry.backoff.ms":  config.RetryBackoff,
+		"socket.timeout.ms": config.TimeoutMS,
+		"acks":              "1", // Wait for leader acknowledgment
+	}
+
+	if err := applySecurityConfig(producerConfig, config); err != nil {
+		return nil, err
+	}
+
+	compression, err := normalizeCompression(config.Compression)
+	if err != nil {
+		return nil, err
+	}
+	if err := producerConfig.SetKey("compression.type", compression); err != nil {
+		return nil, fmt.Errorf("failed to set compression.type: %v", err)
+	}
+
+	fmt.Printf("Configuring confluent Kafka producer with broker: %s (security protocol: %s)\n",
+		config.Broker, config.SecurityProtocol)
+
+	producer, err := kafka.NewProducer(producerConfig)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create Kafka producer: %v", err)
+	}
+
+	return &ConfluentSink{producer: producer, topic: config.Topic}, nil
+}
+
+// applySecurityConfig wires SASL/TLS settings from config into a confluent
+// ConfigMap, matching the librdkafka property names documented for
+// security.protocol, sasl.mechanism, and the ssl.* certificate options.
+func applySecurityConfig(producerConfig *kafka.ConfigMap, config *KafkaConfig) error {
+	protocol := config.SecurityProtocol
+	if protocol == "" {
+		protocol = "PLAINTEXT"
+	}
+	if err := producerConfig.SetKey("security.protocol", protocol); err != nil {
+		return fmt.Errorf("failed to set security.protocol: %v", err)
+	}
+
+	if protocol == "SASL_PLAINTEXT" || protocol == "SASL_SSL" {
+		if config.SASLMechanism == "" {
+			return fmt.Errorf("sasl_mechanism is required when security_protocol is %s", protocol)
+		}
+		if err := producerConfig.SetKey("sasl.mechanism", config.SASLMechanism); err != nil {
+			return fmt.Errorf("failed to set sasl.mechanism: %v", err)
+		}
+		if err := producerConfig.SetKey("sasl.username", config.Username); err != nil {
+			return fmt.Errorf("failed to set sasl.username: %v", err)
+		}
+		if err := producerConfig.SetKey("sasl.password", config.Password); err != nil {
+			return fmt.Errorf("failed to set sasl.password: %v", err)
+		}
+	}
+
+	if protocol == "SSL" || protocol == "SASL_SSL" {
+		if config.CAFile != "" {
+			if err := producerConfig.SetKey("ssl.ca.location", config.CAFile); err != nil {
+				return fmt.Errorf("failed to set ssl.ca.location: %v", err)
+			}
+		}
+		if config.CertFile != "" {
+			if err := producerConfig.SetKey("ssl.certificate.location", config.CertFile); err != nil {
+				return fmt.Errorf("failed to set ssl.certificate.location: %v", err)
+			}
+		}
+		if config.KeyFile != "" {
+			if err := producerConfig.SetKey("ssl.key.location", config.KeyFile); err != nil {
+				return fmt.Errorf("failed to set ssl.key.location: %v", err)
+			}
+		}
+		if config.InsecureSkipVerify {
+			if err := producerConfig.SetKey("enable.ssl.certificate.verification", false); err != nil {
+				return fmt.Errorf("failed to set enable.ssl.certificate.verification: %v", err)
+			}
+		}
+	}
+
+	return nil
+}
+
+// Send publishes value under key and blocks until the broker confirms (or
+// rejects) delivery of the message.
+func (s *ConfluentSink) Send(ctx context.Context, key, value []byte, headers []Header) error {
+	deliveryChan := make(chan kafka.Event, 1)
+	msg := &kafka.Message{
+		TopicPartition: kafka.TopicPartition{Topic: &s.topic, Partition: kafka.PartitionAny},
+		Key:            key,
+		Value:          value,
+		Timestamp:      time.Now(),
+		Headers:        toKafkaHeaders(headers),
+	}
+
+	if err := s.producer.Produce(msg, deliveryChan); err != nil {
+		return fmt.Errorf("failed to send message to Kafka: %v", err)
+	}
+
+	select {
+	case e := <-deliveryChan:
+		report, ok := e.(*kafka.Message)
+		if !ok {
+			return fmt.Errorf("unexpected delivery event type %T", e)
+		}
+		if report.TopicPartition.Error != nil {
+			return fmt.Errorf("failed to deliver message to Kafka: %v", report.TopicPartition.Error)
+		}
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// Close flushes outstanding deliveries and releases the producer.
+func (s *ConfluentSink) Close() error {
+	s.producer.Flush(5000)
+	s.producer.Close()
+	return nil
+}
+
+func toKafkaHeaders(headers []Header) []kafka.Header {
+	if len(headers) == 0 {
+		return nil
+	}
+	out := make([]kafka.Header, len(headers))
+	for i, h := range headers {
+		out[i] = kafka.Header{Key: h.Key, Value: h.Value}
+	}
+	return out
+}
@@ -0,0 +1,14 @@
+//go:build !confluent
+
+package main
+
+import "fmt"
+
+// NewConfluentSink is a stub used when the binary is built without the
+// confluent build tag, so the cgo/librdkafka dependency confluent-kafka-go
+// requires doesn't have to be satisfied by users who only want the
+// sarama, franzgo, or stdout drivers. Build with -tags confluent to get
+// the real implementation in confluent_sink.go.
+func NewConfluentSink(config *KafkaConfig) (Sink, error) {
+	return nil, fmt.Errorf("driver %q requires building with -tags confluent", config.Driver)
+}
@@ -0,0 +1,33 @@
+package main
+
+import "strings"
+
+// LineBuffer accumulates raw bytes read from the log file across chunks
+// and splits them into complete lines, carrying any trailing partial
+// line over to the next call. Without this, a log line that straddles two
+// chunks would otherwise be split across two Kafka messages.
+type LineBuffer struct {
+	pending []byte
+}
+
+// Split appends chunk to any previously buffered partial line and returns
+// the complete lines found within, in the order they appear. Any trailing
+// bytes that don't yet end in a newline are retained for the next call.
+func (b *LineBuffer) Split(chunk []byte) []string {
+	b.pending = append(b.pending, chunk...)
+
+	var lines []string
+	start := 0
+	for i := 0; i < len(b.pending); i++ {
+		if b.pending[i] != '\n' {
+			continue
+		}
+		line := strings.TrimSuffix(string(b.pending[start:i]), "\r")
+		if line != "" {
+			lines = append(lines, line)
+		}
+		start = i + 1
+	}
+	b.pending = append([]byte(nil), b.pending[start:]...)
+	return lines
+}
@@ -0,0 +1,71 @@
+package main
+
+import "testing"
+
+func TestLineBufferSplitCompleteLines(t *testing.T) {
+	b := &LineBuffer{}
+	lines := b.Split([]byte("one\ntwo\nthree\n"))
+	want := []string{"one", "two", "three"}
+	if !equalLines(lines, want) {
+		t.Fatalf("Split() = %v; want %v", lines, want)
+	}
+	if len(b.pending) != 0 {
+		t.Fatalf("pending = %q; want empty after only complete lines", b.pending)
+	}
+}
+
+func TestLineBufferSplitAcrossChunkBoundary(t *testing.T) {
+	b := &LineBuffer{}
+
+	lines := b.Split([]byte("one\ntw"))
+	if !equalLines(lines, []string{"one"}) {
+		t.Fatalf("Split() = %v; want [one]", lines)
+	}
+
+	lines = b.Split([]byte("o\nthree\n"))
+	if !equalLines(lines, []string{"two", "three"}) {
+		t.Fatalf("Split() = %v; want [two three]", lines)
+	}
+}
+
+func TestLineBufferSplitNoTrailingNewlineHeldBack(t *testing.T) {
+	b := &LineBuffer{}
+
+	lines := b.Split([]byte("partial"))
+	if len(lines) != 0 {
+		t.Fatalf("Split() = %v; want no lines until a newline arrives", lines)
+	}
+
+	lines = b.Split([]byte(" line\n"))
+	if !equalLines(lines, []string{"partial line"}) {
+		t.Fatalf("Split() = %v; want [partial line]", lines)
+	}
+}
+
+func TestLineBufferSplitStripsTrailingCR(t *testing.T) {
+	b := &LineBuffer{}
+	lines := b.Split([]byte("windows\r\nstyle\r\n"))
+	if !equalLines(lines, []string{"windows", "style"}) {
+		t.Fatalf("Split() = %v; want CR stripped", lines)
+	}
+}
+
+func TestLineBufferSplitSkipsBlankLines(t *testing.T) {
+	b := &LineBuffer{}
+	lines := b.Split([]byte("a\n\nb\n"))
+	if !equalLines(lines, []string{"a", "b"}) {
+		t.Fatalf("Split() = %v; want blank lines skipped", lines)
+	}
+}
+
+func equalLines(got, want []string) bool {
+	if len(got) != len(want) {
+		return false
+	}
+	for i := range got {
+		if got[i] != want[i] {
+			return false
+		}
+	}
+	return true
+}
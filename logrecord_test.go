@@ -0,0 +1,127 @@
+package main
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func TestLogLineParserDefaultPattern(t *testing.T) {
+	parser, err := NewLogLineParser(nil)
+	if err != nil {
+		t.Fatalf("NewLogLineParser(nil) error = %v", err)
+	}
+
+	line := "2024-01-02 03:04:05,678 INFO FSNamesystem.audit: allowed=true ugi=hdfs ip=/10.0.0.1 cmd=open src=/a/b dst=null perm=null"
+	record := parser.Parse(line)
+
+	if record.Timestamp != "2024-01-02 03:04:05,678" {
+		t.Errorf("Timestamp = %q; want %q", record.Timestamp, "2024-01-02 03:04:05,678")
+	}
+	if record.Level != "INFO" {
+		t.Errorf("Level = %q; want %q", record.Level, "INFO")
+	}
+	if record.Component != "FSNamesystem.audit" {
+		t.Errorf("Component = %q; want %q", record.Component, "FSNamesystem.audit")
+	}
+	wantMessage := "allowed=true ugi=hdfs ip=/10.0.0.1 cmd=open src=/a/b dst=null perm=null"
+	if record.Message != wantMessage {
+		t.Errorf("Message = %q; want %q", record.Message, wantMessage)
+	}
+	if got := record.Field("src"); got != "/a/b" {
+		t.Errorf("Field(%q) = %q; want %q", "src", got, "/a/b")
+	}
+	if got := record.Field("cmd"); got != "open" {
+		t.Errorf("Field(%q) = %q; want %q", "cmd", got, "open")
+	}
+}
+
+func TestLogLineParserNonMatchingLineKeepsRawMessage(t *testing.T) {
+	parser, err := NewLogLineParser(nil)
+	if err != nil {
+		t.Fatalf("NewLogLineParser(nil) error = %v", err)
+	}
+
+	line := "not a log4j line at all"
+	record := parser.Parse(line)
+
+	if record.Message != line {
+		t.Errorf("Message = %q; want raw line %q", record.Message, line)
+	}
+	if record.Timestamp != "" || record.Level != "" || record.Component != "" {
+		t.Errorf("record = %+v; want only Message populated for a non-matching line", record)
+	}
+}
+
+func TestLogLineParserCustomPattern(t *testing.T) {
+	format := &LogFormatConfig{
+		Pattern: `^(?P<level>[A-Z]+)\|(?P<thread>[\w-]+)\|(?P<message>.*)$`,
+	}
+	parser, err := NewLogLineParser(format)
+	if err != nil {
+		t.Fatalf("NewLogLineParser() error = %v", err)
+	}
+
+	record := parser.Parse("WARN|datanode-1|blockid=blk_123 size=456")
+	if record.Level != "WARN" {
+		t.Errorf("Level = %q; want %q", record.Level, "WARN")
+	}
+	if record.Thread != "datanode-1" {
+		t.Errorf("Thread = %q; want %q", record.Thread, "datanode-1")
+	}
+	if got := record.Field("blockid"); got != "blk_123" {
+		t.Errorf("Field(%q) = %q; want %q", "blockid", got, "blk_123")
+	}
+}
+
+func TestNewLogLineParserInvalidPattern(t *testing.T) {
+	format := &LogFormatConfig{Pattern: "("}
+	if _, err := NewLogLineParser(format); err == nil {
+		t.Fatal("NewLogLineParser() error = nil; want error for invalid regex")
+	}
+}
+
+func TestEncodeRecordUsesKeyFieldWhenPresent(t *testing.T) {
+	record := LogRecord{Message: "m", Fields: map[string]string{"src": "/a/b"}}
+	format := &LogFormatConfig{KeyField: "src"}
+
+	key, value, err := encodeRecord(record, format)
+	if err != nil {
+		t.Fatalf("encodeRecord() error = %v", err)
+	}
+	if string(key) != "/a/b" {
+		t.Errorf("key = %q; want %q", key, "/a/b")
+	}
+
+	var got LogRecord
+	if err := json.Unmarshal(value, &got); err != nil {
+		t.Fatalf("value isn't valid JSON: %v", err)
+	}
+	if got.Message != record.Message {
+		t.Errorf("decoded Message = %q; want %q", got.Message, record.Message)
+	}
+}
+
+func TestEncodeRecordFallsBackToGeneratedKeyWhenFieldMissing(t *testing.T) {
+	record := LogRecord{Message: "m"}
+	format := &LogFormatConfig{KeyField: "src"}
+
+	key, _, err := encodeRecord(record, format)
+	if err != nil {
+		t.Fatalf("encodeRecord() error = %v", err)
+	}
+	if len(key) == 0 {
+		t.Fatal("key is empty; want a generated fallback key")
+	}
+}
+
+func TestEncodeRecordNilFormatGeneratesKey(t *testing.T) {
+	record := LogRecord{Message: "m"}
+
+	key, _, err := encodeRecord(record, nil)
+	if err != nil {
+		t.Fatalf("encodeRecord() error = %v", err)
+	}
+	if len(key) == 0 {
+		t.Fatal("key is empty; want a generated fallback key")
+	}
+}
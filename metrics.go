@@ -0,0 +1,204 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+var (
+	fileSizeBytes = promauto.NewGauge(prometheus.GaugeOpts{
+		Name: "hlogmonitor_file_size_bytes",
+		Help: "Current size of the monitored log file in bytes.",
+	})
+	filePositionBytes = promauto.NewGauge(prometheus.GaugeOpts{
+		Name: "hlogmonitor_file_position_bytes",
+		Help: "Byte offset the monitor has read up to.",
+	})
+	lagBytes = promauto.NewGauge(prometheus.GaugeOpts{
+		Name: "hlogmonitor_lag_bytes",
+		Help: "Bytes written to the log file that have not yet been read (size - position).",
+	})
+	linesReadTotal = promauto.NewCounter(prometheus.CounterOpts{
+		Name: "hlogmonitor_lines_read_total",
+		Help: "Total number of log lines read from the monitored file.",
+	})
+	kafkaMessagesSentTotal = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "hlogmonitor_kafka_messages_sent_total",
+		Help: "Total number of messages sent through the sink, by result.",
+	}, []string{"result"})
+	kafkaDeliveryLatencySeconds = promauto.NewHistogram(prometheus.HistogramOpts{
+		Name:    "hlogmonitor_kafka_delivery_latency_seconds",
+		Help:    "Time from Send() being called to the sink confirming or failing delivery.",
+		Buckets: prometheus.DefBuckets,
+	})
+	kafkaErrorsTotal = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "hlogmonitor_kafka_errors_total",
+		Help: "Total number of sink delivery errors, by error code.",
+	}, []string{"code"})
+	rotationsTotal = promauto.NewCounter(prometheus.CounterOpts{
+		Name: "hlogmonitor_rotations_total",
+		Help: "Total number of log rotations detected by the tailer.",
+	})
+	queueDepth = promauto.NewGauge(prometheus.GaugeOpts{
+		Name: "hlogmonitor_queue_depth",
+		Help: "Number of encoded records currently buffered in the batch queue.",
+	})
+	queueDropsTotal = promauto.NewGauge(prometheus.GaugeOpts{
+		Name: "hlogmonitor_queue_drops_total",
+		Help: "Total number of records dropped because the batch queue was full.",
+	})
+)
+
+// instrumentedSink wraps a Sink to record delivery metrics and feed the
+// readiness tracker, without any of the concrete sink implementations
+// needing to know about Prometheus.
+type instrumentedSink struct {
+	inner     Sink
+	readiness *ReadinessTracker
+}
+
+// instrumentSink wraps inner so every Send() call is timed and counted,
+// and reported to readiness.
+func instrumentSink(inner Sink, readiness *ReadinessTracker) Sink {
+	return &instrumentedSink{inner: inner, readiness: readiness}
+}
+
+func (s *instrumentedSink) Send(ctx context.Context, key, value []byte, headers []Header) error {
+	start := time.Now()
+	err := s.inner.Send(ctx, key, value, headers)
+	kafkaDeliveryLatencySeconds.Observe(time.Since(start).Seconds())
+	s.readiness.RecordDelivery(err)
+
+	if err != nil {
+		kafkaMessagesSentTotal.WithLabelValues("error").Inc()
+		kafkaErrorsTotal.WithLabelValues("send_failed").Inc()
+		return err
+	}
+	kafkaMessagesSentTotal.WithLabelValues("success").Inc()
+	return nil
+}
+
+func (s *instrumentedSink) Close() error {
+	return s.inner.Close()
+}
+
+// ReadinessTracker decides whether the monitor should report ready. It
+// flips to unhealthy when the sink delivery error rate exceeds
+// errorRateThreshold, or when observed lag has grown for maxLagGrowthStreak
+// consecutive checks in a row, so an orchestrator can restart the pod.
+type ReadinessTracker struct {
+	errorRateThreshold float64
+	maxLagGrowthStreak int
+
+	mu        sync.Mutex
+	successes int64
+	errors    int64
+	lastLag   int64
+	lagStreak int
+	ready     bool
+}
+
+// NewReadinessTracker creates a ReadinessTracker. A non-positive
+// maxLagGrowthStreak disables the lag-growth check.
+func NewReadinessTracker(errorRateThreshold float64, maxLagGrowthStreak int) *ReadinessTracker {
+	return &ReadinessTracker{
+		errorRateThreshold: errorRateThreshold,
+		maxLagGrowthStreak: maxLagGrowthStreak,
+		ready:              true,
+	}
+}
+
+// RecordDelivery updates the running error rate with the outcome of one
+// sink delivery.
+func (r *ReadinessTracker) RecordDelivery(err error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if err != nil {
+		r.errors++
+	} else {
+		r.successes++
+	}
+	r.recompute()
+}
+
+// RecordLag updates the lag-growth streak with the latest observed lag
+// (file size minus read position).
+func (r *ReadinessTracker) RecordLag(lag int64) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if lag > r.lastLag {
+		r.lagStreak++
+	} else {
+		r.lagStreak = 0
+	}
+	r.lastLag = lag
+	r.recompute()
+}
+
+func (r *ReadinessTracker) recompute() {
+	total := r.successes + r.errors
+	errorRate := 0.0
+	if total > 0 {
+		errorRate = float64(r.errors) / float64(total)
+	}
+	r.ready = errorRate <= r.errorRateThreshold &&
+		(r.maxLagGrowthStreak <= 0 || r.lagStreak < r.maxLagGrowthStreak)
+}
+
+// Ready reports the current readiness state.
+func (r *ReadinessTracker) Ready() bool {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.ready
+}
+
+// MetricsServer exposes Prometheus metrics plus liveness/readiness probes
+// over HTTP.
+type MetricsServer struct {
+	server *http.Server
+}
+
+// NewMetricsServer builds a MetricsServer listening on addr. /healthz
+// always reports ok; /readyz reflects readiness.Ready().
+func NewMetricsServer(addr string, readiness *ReadinessTracker) *MetricsServer {
+	mux := http.NewServeMux()
+	mux.Handle("/metrics", promhttp.Handler())
+	mux.HandleFunc("/healthz", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+		fmt.Fprintln(w, "ok")
+	})
+	mux.HandleFunc("/readyz", func(w http.ResponseWriter, r *http.Request) {
+		if !readiness.Ready() {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			fmt.Fprintln(w, "not ready")
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+		fmt.Fprintln(w, "ready")
+	})
+
+	return &MetricsServer{server: &http.Server{Addr: addr, Handler: mux}}
+}
+
+// Start begins serving in the background. Errors other than a clean
+// Stop-triggered shutdown are logged.
+func (m *MetricsServer) Start() {
+	go func() {
+		if err := m.server.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+			log.Printf("Metrics server error: %v", err)
+		}
+	}()
+}
+
+// Stop gracefully shuts the metrics server down.
+func (m *MetricsServer) Stop(ctx context.Context) error {
+	return m.server.Shutdown(ctx)
+}
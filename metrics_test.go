@@ -0,0 +1,147 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestReadinessTrackerErrorRateThreshold(t *testing.T) {
+	tests := []struct {
+		name      string
+		threshold float64
+		outcomes  []error // nil = success
+		wantReady bool
+	}{
+		{
+			name:      "all successes stays ready",
+			threshold: 0.5,
+			outcomes:  []error{nil, nil, nil},
+			wantReady: true,
+		},
+		{
+			name:      "error rate at threshold stays ready",
+			threshold: 0.5,
+			outcomes:  []error{nil, errors.New("boom")},
+			wantReady: true,
+		},
+		{
+			name:      "error rate over threshold goes unready",
+			threshold: 0.5,
+			outcomes:  []error{nil, errors.New("a"), errors.New("b")},
+			wantReady: false,
+		},
+		{
+			name:      "no deliveries yet stays ready",
+			threshold: 0,
+			outcomes:  nil,
+			wantReady: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := NewReadinessTracker(tt.threshold, 0)
+			for _, err := range tt.outcomes {
+				r.RecordDelivery(err)
+			}
+			if got := r.Ready(); got != tt.wantReady {
+				t.Errorf("Ready() = %v; want %v", got, tt.wantReady)
+			}
+		})
+	}
+}
+
+func TestReadinessTrackerLagGrowthStreak(t *testing.T) {
+	tests := []struct {
+		name               string
+		maxLagGrowthStreak int
+		lags               []int64
+		wantReady          bool
+	}{
+		{
+			name:               "lag growth disabled when streak limit is zero",
+			maxLagGrowthStreak: 0,
+			lags:               []int64{1, 2, 3, 4, 5},
+			wantReady:          true,
+		},
+		{
+			name:               "growth below streak limit stays ready",
+			maxLagGrowthStreak: 3,
+			lags:               []int64{1, 2},
+			wantReady:          true,
+		},
+		{
+			name:               "growth reaching streak limit goes unready",
+			maxLagGrowthStreak: 3,
+			lags:               []int64{1, 2, 3},
+			wantReady:          false,
+		},
+		{
+			name:               "a non-growing lag resets the streak",
+			maxLagGrowthStreak: 3,
+			lags:               []int64{1, 2, 2, 3, 4},
+			wantReady:          true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := NewReadinessTracker(1, tt.maxLagGrowthStreak)
+			for _, lag := range tt.lags {
+				r.RecordLag(lag)
+			}
+			if got := r.Ready(); got != tt.wantReady {
+				t.Errorf("Ready() = %v; want %v", got, tt.wantReady)
+			}
+		})
+	}
+}
+
+func TestReadinessTrackerCombinesErrorRateAndLag(t *testing.T) {
+	r := NewReadinessTracker(0.5, 2)
+	r.RecordDelivery(nil)
+	r.RecordLag(1)
+	if !r.Ready() {
+		t.Fatal("Ready() = false; want true before any threshold is crossed")
+	}
+
+	r.RecordLag(2)
+	if r.Ready() {
+		t.Fatal("Ready() = true; want false once the lag-growth streak hits the limit")
+	}
+}
+
+func TestMetricsServerHealthzAndReadyz(t *testing.T) {
+	readiness := NewReadinessTracker(0, 0)
+	server := NewMetricsServer("127.0.0.1:0", readiness)
+	server.Start()
+	defer server.Stop(context.Background())
+
+	// Exercise the handlers directly rather than over the network, since
+	// the server was given an ephemeral port it doesn't expose here.
+	rec := httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodGet, "/healthz", nil)
+	server.server.Handler.ServeHTTP(rec, req)
+	if rec.Code != http.StatusOK {
+		t.Errorf("/healthz status = %d; want %d", rec.Code, http.StatusOK)
+	}
+
+	rec = httptest.NewRecorder()
+	req = httptest.NewRequest(http.MethodGet, "/readyz", nil)
+	server.server.Handler.ServeHTTP(rec, req)
+	if rec.Code != http.StatusOK {
+		t.Errorf("/readyz status = %d; want %d when ready", rec.Code, http.StatusOK)
+	}
+
+	readiness.RecordDelivery(errors.New("boom"))
+
+	rec = httptest.NewRecorder()
+	req = httptest.NewRequest(http.MethodGet, "/readyz", nil)
+	server.server.Handler.ServeHTTP(rec, req)
+	if rec.Code != http.StatusServiceUnavailable {
+		t.Errorf("/readyz status = %d; want %d once readiness drops", rec.Code, http.StatusServiceUnavailable)
+	}
+}
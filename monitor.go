@@ -1,6 +1,7 @@
 package main
 
 import (
+	"context"
 	"encoding/json"
 	"flag"
 	"fmt"
@@ -9,25 +10,81 @@ import (
 	"os"
 	"path/filepath"
 	"time"
-
-	"github.com/confluentinc/confluent-kafka-go/kafka"
 )
 
 // KafkaConfig holds the configuration for Kafka connection
 type KafkaConfig struct {
-	Broker       string `json:"broker"`
-	Topic        string `json:"topic"`
-	ClientID     string `json:"client_id"`
-	MaxRetry     int    `json:"max_retry"`
-	RetryBackoff int    `json:"retry_backoff_ms"`
-	TimeoutMS    int    `json:"timeout_ms"`
+	Driver       string           `json:"driver"`
+	Broker       string           `json:"broker"`
+	Topic        string           `json:"topic"`
+	ClientID     string           `json:"client_id"`
+	MaxRetry     int              `json:"max_retry"`
+	RetryBackoff int              `json:"retry_backoff_ms"`
+	TimeoutMS    int              `json:"timeout_ms"`
+	LogFormat    *LogFormatConfig `json:"log_format"`
+
+	// SecurityProtocol is one of PLAINTEXT, SASL_PLAINTEXT, SASL_SSL, or
+	// SSL. Defaults to PLAINTEXT. This and the SASL/TLS fields below are
+	// applied only by the confluent driver; the sarama, franzgo, and stdout
+	// drivers ignore them.
+	SecurityProtocol string `json:"security_protocol"`
+	// SASLMechanism is one of PLAIN, SCRAM-SHA-256, or SCRAM-SHA-512. Only
+	// used when SecurityProtocol enables SASL.
+	SASLMechanism string `json:"sasl_mechanism"`
+	Username      string `json:"username"`
+	Password      string `json:"password"`
+	// CAFile, CertFile, and KeyFile are PEM file paths used when
+	// SecurityProtocol enables TLS.
+	CAFile             string `json:"ca_file"`
+	CertFile           string `json:"cert_file"`
+	KeyFile            string `json:"key_file"`
+	InsecureSkipVerify bool   `json:"insecure_skip_verify"`
+
+	// QueueSize bounds how many encoded records may be buffered between the
+	// tailer and the sink.
+	QueueSize int `json:"queue_size"`
+	// LingerMS is how long the batcher waits to accumulate BatchBytes
+	// before flushing a partial batch anyway.
+	LingerMS int `json:"linger_ms"`
+	// BatchBytes is the size at which a group of buffered records is
+	// flushed to the sink.
+	BatchBytes int `json:"batch_bytes"`
+	// Compression is the native codec the selected driver's client applies
+	// to the batches it produces: "none", "gzip", "snappy", "lz4", or
+	// "zstd". Ignored by the stdout driver, which always writes plain
+	// newline-delimited JSON.
+	Compression string `json:"compression"`
+	// DropWhenFull makes Enqueue drop (and count) records instead of
+	// blocking the tailer when the queue is full.
+	DropWhenFull bool `json:"drop_when_full"`
+
+	// MetricsAddr, if set, is the address (e.g. ":9090") the Prometheus
+	// /metrics, /healthz, and /readyz endpoints are served on. Leave empty
+	// to disable the metrics server.
+	MetricsAddr string `json:"metrics_addr"`
+	// ErrorRateThreshold is the sink delivery error rate above which
+	// /readyz reports unhealthy.
+	ErrorRateThreshold float64 `json:"error_rate_threshold"`
+	// LagGrowthLimit is the number of consecutive checks of growing lag
+	// at which /readyz starts reporting unhealthy. Non-positive disables
+	// this check.
+	LagGrowthLimit int `json:"lag_growth_limit"`
+
+	// CheckpointTopic, if set, additionally commits checkpoints to this
+	// Kafka topic via KafkaOffsetStore, using the same driver and
+	// connection settings as the log sink, so the last confirmed offset
+	// survives the loss of the local checkpoint file too. The local file
+	// remains the source of truth for resuming. Leave empty to checkpoint
+	// to disk only.
+	CheckpointTopic string `json:"checkpoint_topic"`
 }
 
 func main() {
 	// Define command line flags
 	logFilePath := flag.String("file", "", "Path to HDFS log file to monitor")
-	checkInterval := flag.Int("interval", 5, "Check interval in seconds")
 	configFilePath := flag.String("config", "kafka_config.json", "Path to Kafka configuration file")
+	checkpointPath := flag.String("checkpoint", "", "Path to checkpoint file (defaults to <file>.offset)")
+	checkpointInterval := flag.Int("checkpoint-interval", 10, "Checkpoint save interval in seconds")
 	flag.Parse()
 
 	// Validate log file path
@@ -52,93 +109,173 @@ func main() {
 		log.Fatalf("Failed to load Kafka configuration: %v", err)
 	}
 
-	// Create Kafka producer
-	producer, err := createKafkaProducer(kafkaConfig)
+	// Create the sink that records are published through, instrumented so
+	// delivery outcomes feed both Prometheus and the readiness tracker
+	rawSink, err := NewSink(kafkaConfig)
 	if err != nil {
-		log.Fatalf("Failed to create Kafka producer: %v", err)
+		log.Fatalf("Failed to create Kafka sink: %v", err)
+	}
+	defer rawSink.Close()
+
+	readiness := NewReadinessTracker(kafkaConfig.ErrorRateThreshold, kafkaConfig.LagGrowthLimit)
+	sink := instrumentSink(rawSink, readiness)
+
+	if kafkaConfig.MetricsAddr != "" {
+		metricsServer := NewMetricsServer(kafkaConfig.MetricsAddr, readiness)
+		metricsServer.Start()
+		fmt.Printf("Serving metrics on %s\n", kafkaConfig.MetricsAddr)
+		defer func() {
+			ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+			defer cancel()
+			if err := metricsServer.Stop(ctx); err != nil {
+				log.Printf("Warning: failed to stop metrics server: %v", err)
+			}
+		}()
 	}
-	defer producer.Close()
 
 	fmt.Printf("Starting to monitor HDFS log file: %s\n", absPath)
-	fmt.Printf("Checking for updates every %d seconds\n", *checkInterval)
 	fmt.Printf("Sending log updates to Kafka topic: %s\n", kafkaConfig.Topic)
 
-	// Get initial file info
-	lastSize, lastModTime := getFileInfo(absPath)
-	fmt.Printf("Initial file size: %d bytes, last modified: %s\n", lastSize, lastModTime)
-
-	// Start a goroutine to handle message delivery reports
-	deliveryChan := make(chan kafka.Event)
-	go handleDeliveryReports(deliveryChan)
-
-	// Monitor loop
-	ticker := time.NewTicker(time.Duration(*checkInterval) * time.Second)
-	defer ticker.Stop()
-
-	topic := kafkaConfig.Topic
-	for range ticker.C {
-		currentSize, currentModTime := getFileInfo(absPath)
-
-		if currentSize > lastSize {
-			bytesAdded := currentSize - lastSize
-			fmt.Printf("[%s] File updated: %d new bytes added (total size: %d bytes)\n",
-				time.Now().Format("2006-01-02 15:04:05"), bytesAdded, currentSize)
-
-			// Read and send the new content to Kafka
-			newContent := readNewContent(absPath, lastSize, currentSize)
-			if newContent != "" {
-				sendToKafka(producer, topic, newContent, deliveryChan)
-			}
-
-			lastSize = currentSize
-			lastModTime = currentModTime
-		} else if currentModTime.After(lastModTime) {
-			fmt.Printf("[%s] File modified but size unchanged (size: %d bytes)\n",
-				time.Now().Format("2006-01-02 15:04:05"), currentSize)
-			lastModTime = currentModTime
+	// Set up the checkpoint store and resume from it if possible
+	cpPath := *checkpointPath
+	if cpPath == "" {
+		cpPath = absPath + ".offset"
+	}
+	var offsetStore OffsetStore = NewFileOffsetStore(cpPath)
+	if kafkaConfig.CheckpointTopic != "" {
+		checkpointConfig := *kafkaConfig
+		checkpointConfig.Topic = kafkaConfig.CheckpointTopic
+		checkpointSink, err := NewSink(&checkpointConfig)
+		if err != nil {
+			log.Fatalf("Failed to create checkpoint topic sink: %v", err)
 		}
+		defer checkpointSink.Close()
+		offsetStore = NewCompositeOffsetStore(offsetStore, NewKafkaOffsetStore(checkpointSink, absPath))
 	}
-}
+	defer offsetStore.Close()
 
-// getFileInfo returns the size and modification time of a file
-func getFileInfo(filePath string) (int64, time.Time) {
-	fileInfo, err := os.Stat(filePath)
+	fileInfo, err := os.Stat(absPath)
 	if err != nil {
-		log.Printf("Warning: Failed to get file info: %v", err)
-		return 0, time.Time{}
+		log.Fatalf("Failed to stat log file: %v", err)
 	}
-	return fileInfo.Size(), fileInfo.ModTime()
-}
+	inode, device := fileIdentity(fileInfo)
+
+	var lastSize int64
+	var lastModTime time.Time
+	var lastKafkaOffset int64
 
-// readNewContent reads new content added to the file and returns it as a string
-func readNewContent(filePath string, oldSize, newSize int64) string {
-	file, err := os.Open(filePath)
+	cp, err := offsetStore.Load()
 	if err != nil {
-		log.Printf("Warning: Failed to open file: %v", err)
-		return ""
+		log.Printf("Warning: failed to load checkpoint, starting from the beginning of the file: %v", err)
+	}
+	if cp != nil && cp.Inode == inode && cp.Device == device {
+		fmt.Printf("Resuming from checkpoint: offset %d (same file as last run)\n", cp.Size)
+		lastSize = cp.Size
+		lastModTime = cp.ModTime
+		lastKafkaOffset = cp.LastKafkaOffset
+	} else {
+		if cp != nil {
+			fmt.Printf("Log file was rotated since last checkpoint; starting from 0\n")
+		}
+		lastModTime = fileInfo.ModTime()
+		lastSize = 0
+	}
+	fmt.Printf("Starting offset: %d bytes, last modified: %s\n", lastSize, lastModTime)
+
+	// The tracker only lets a checkpoint take effect once the sink has
+	// confirmed every record it implies, so a crash never advances past
+	// data that was merely enqueued.
+	tracker := NewCheckpointTracker(Checkpoint{
+		Inode:           inode,
+		Device:          device,
+		Size:            lastSize,
+		ModTime:         lastModTime,
+		LastKafkaOffset: lastKafkaOffset,
+	})
+	saveCheckpoint := func() {
+		if err := offsetStore.Save(tracker.Snapshot()); err != nil {
+			log.Printf("Warning: failed to save checkpoint: %v", err)
+		}
 	}
-	defer file.Close()
 
-	// Seek to the position of the old file size
-	_, err = file.Seek(oldSize, 0)
+	// Build the line parser that turns raw bytes into structured records
+	parser, err := NewLogLineParser(kafkaConfig.LogFormat)
 	if err != nil {
-		log.Printf("Warning: Failed to seek in file: %v", err)
-		return ""
+		log.Fatalf("Failed to build log line parser: %v", err)
 	}
+	lineBuffer := &LineBuffer{}
+
+	// The batch queue paces delivery to the sink in size/time-bounded
+	// groups, still sending one record per line; MarkDone reports each
+	// record back to the tracker once the sink has actually confirmed it
+	batchQueue := NewBatchQueue(sink, kafkaConfig, tracker.MarkDone)
+	go batchQueue.Run()
+	defer batchQueue.Close()
 
-	// Read the new content
-	newContent := make([]byte, newSize-oldSize)
-	_, err = file.Read(newContent)
+	// Start tailing the file with fsnotify instead of polling
+	tailer, err := NewTailer(absPath, lastSize)
 	if err != nil {
-		log.Printf("Warning: Failed to read new content: %v", err)
-		return ""
+		log.Fatalf("Failed to start tailer: %v", err)
 	}
+	defer tailer.Close()
 
-	fmt.Println("--- New content ---")
-	fmt.Println(string(newContent))
-	fmt.Println("------------------")
+	stop := make(chan struct{})
+	go tailer.Run(stop)
+	defer close(stop)
 
-	return string(newContent)
+	go func() {
+		for err := range tailer.Errs() {
+			log.Printf("Tailer error: %v", err)
+		}
+	}()
+
+	// Periodically checkpoint even if no deliveries have landed recently,
+	// and feed the tailer's observed lag (bytes written but not yet read)
+	// into the readiness tracker
+	checkpointTicker := time.NewTicker(time.Duration(*checkpointInterval) * time.Second)
+	defer checkpointTicker.Stop()
+	go func() {
+		for range checkpointTicker.C {
+			saveCheckpoint()
+			queueDepth.Set(float64(batchQueue.Depth()))
+			queueDropsTotal.Set(float64(batchQueue.Drops()))
+			readiness.RecordLag(tailer.LagBytes())
+		}
+	}()
+
+	// Monitor loop: drain chunks as the tailer delivers them
+	var seq uint64
+	for chunk := range tailer.Chunks() {
+		fmt.Printf("[%s] File updated: %d bytes read (total size: %d bytes)\n",
+			time.Now().Format("2006-01-02 15:04:05"), len(chunk.Data), chunk.Size)
+
+		// Split the chunk into complete lines and hand each encoded record
+		// to the batch queue, tagged with the sequence number the tracker
+		// needs to know once it has actually been sent.
+		lines := lineBuffer.Split(chunk.Data)
+		for _, line := range lines {
+			record := parser.Parse(line)
+			key, value, err := encodeRecord(record, kafkaConfig.LogFormat)
+			if err != nil {
+				log.Printf("Failed to encode log record: %v", err)
+				continue
+			}
+			seq++
+			lastKafkaOffset++
+			batchQueue.Enqueue(QueuedRecord{Seq: seq, Key: key, Value: value})
+		}
+		linesReadTotal.Add(float64(len(lines)))
+
+		// This chunk's checkpoint only takes effect once every record
+		// enqueued above (seq) has been confirmed sent by the sink.
+		tracker.Enqueue(seq, Checkpoint{
+			Inode:           chunk.Inode,
+			Device:          chunk.Device,
+			Size:            chunk.Size,
+			ModTime:         chunk.ModTime,
+			LastKafkaOffset: lastKafkaOffset,
+		})
+	}
 }
 
 // loadKafkaConfig loads the Kafka configuration from a JSON file
@@ -160,6 +297,9 @@ func loadKafkaConfig(configFilePath string) (*KafkaConfig, error) {
 	}
 
 	// Set defaults if not specified
+	if config.Driver == "" {
+		config.Driver = "confluent"
+	}
 	if config.Topic == "" {
 		config.Topic = "hdfslog"
 	}
@@ -175,66 +315,14 @@ func loadKafkaConfig(configFilePath string) (*KafkaConfig, error) {
 	if config.TimeoutMS == 0 {
 		config.TimeoutMS = 5000
 	}
-
-	fmt.Printf("Loaded Kafka broker address: %s\n", config.Broker)
-
-	return &config, nil
-}
-
-// createKafkaProducer creates a new Kafka producer
-func createKafkaProducer(config *KafkaConfig) (*kafka.Producer, error) {
-	// Configure the producer
-	kafkaConfig := &kafka.ConfigMap{
-		"bootstrap.servers": "192.168.100.98:9092",
-		"client.id":         config.ClientID,
-		"retries":           config.MaxRetry,
-		"retry.backoff.ms":  config.RetryBackoff,
-		"socket.timeout.ms": config.TimeoutMS,
-		"acks":              "1", // Wait for leader acknowledgment
+	if config.SecurityProtocol == "" {
+		config.SecurityProtocol = "PLAINTEXT"
 	}
-
-	fmt.Printf("Configuring Kafka producer with broker: %s\n", config.Broker)
-
-	// Create the producer
-	producer, err := kafka.NewProducer(kafkaConfig)
-	if err != nil {
-		return nil, fmt.Errorf("failed to create Kafka producer: %v", err)
+	if config.ErrorRateThreshold == 0 {
+		config.ErrorRateThreshold = 0.1
 	}
 
-	return producer, nil
-}
-
-// handleDeliveryReports processes message delivery reports
-func handleDeliveryReports(deliveryChan chan kafka.Event) {
-	for e := range deliveryChan {
-		switch ev := e.(type) {
-		case *kafka.Message:
-			if ev.TopicPartition.Error != nil {
-				log.Printf("Failed to deliver message to Kafka: %v", ev.TopicPartition.Error)
-			} else {
-				log.Printf("Successfully delivered message to topic %s [partition %d] at offset %v",
-					*ev.TopicPartition.Topic, ev.TopicPartition.Partition, ev.TopicPartition.Offset)
-			}
-		}
-	}
-}
+	fmt.Printf("Loaded Kafka broker address: %s\n", config.Broker)
 
-// sendToKafka sends a message to Kafka
-func sendToKafka(producer *kafka.Producer, topic string, message string, deliveryChan chan kafka.Event) {
-	// Create a message
-	msg := &kafka.Message{
-		TopicPartition: kafka.TopicPartition{
-			Topic:     &topic,
-			Partition: kafka.PartitionAny,
-		},
-		Value:     []byte(message),
-		Key:       []byte(fmt.Sprintf("hdfs-log-%d", time.Now().UnixNano())),
-		Timestamp: time.Now(),
-	}
-
-	// Produce the message
-	err := producer.Produce(msg, deliveryChan)
-	if err != nil {
-		log.Printf("Failed to send message to Kafka: %v", err)
-	}
+	return &config, nil
 }
@@ -0,0 +1,260 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+// Checkpoint captures enough state to resume tailing a log file across
+// restarts without re-delivering already-sent bytes or silently skipping
+// bytes written while the monitor was down.
+type Checkpoint struct {
+	Inode           uint64    `json:"inode"`
+	Device          uint64    `json:"device"`
+	Size            int64     `json:"size"`
+	ModTime         time.Time `json:"mod_time"`
+	LastKafkaOffset int64     `json:"last_kafka_offset"`
+}
+
+// OffsetStore persists and loads the checkpoint for a monitored file.
+type OffsetStore interface {
+	Load() (*Checkpoint, error)
+	Save(cp Checkpoint) error
+	Close() error
+}
+
+// FileOffsetStore persists the checkpoint to a local JSON file, using a
+// write-temp-then-rename so a crash mid-write never leaves a torn file
+// behind.
+type FileOffsetStore struct {
+	path string
+	mu   sync.Mutex
+}
+
+// NewFileOffsetStore creates a FileOffsetStore backed by the file at path.
+func NewFileOffsetStore(path string) *FileOffsetStore {
+	return &FileOffsetStore{path: path}
+}
+
+// Load reads the checkpoint from disk. It returns a nil Checkpoint (and no
+// error) if no checkpoint file exists yet.
+func (s *FileOffsetStore) Load() (*Checkpoint, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	data, err := os.ReadFile(s.path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to read checkpoint file: %v", err)
+	}
+
+	var cp Checkpoint
+	if err := json.Unmarshal(data, &cp); err != nil {
+		return nil, fmt.Errorf("failed to parse checkpoint file: %v", err)
+	}
+	return &cp, nil
+}
+
+// Save atomically persists cp to disk.
+func (s *FileOffsetStore) Save(cp Checkpoint) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	data, err := json.Marshal(cp)
+	if err != nil {
+		return fmt.Errorf("failed to marshal checkpoint: %v", err)
+	}
+
+	dir := filepath.Dir(s.path)
+	tmp, err := os.CreateTemp(dir, ".offset-*.tmp")
+	if err != nil {
+		return fmt.Errorf("failed to create temp checkpoint file: %v", err)
+	}
+	tmpPath := tmp.Name()
+
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		os.Remove(tmpPath)
+		return fmt.Errorf("failed to write temp checkpoint file: %v", err)
+	}
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		os.Remove(tmpPath)
+		return fmt.Errorf("failed to sync temp checkpoint file: %v", err)
+	}
+	if err := tmp.Close(); err != nil {
+		os.Remove(tmpPath)
+		return fmt.Errorf("failed to close temp checkpoint file: %v", err)
+	}
+
+	if err := os.Rename(tmpPath, s.path); err != nil {
+		os.Remove(tmpPath)
+		return fmt.Errorf("failed to rename temp checkpoint file: %v", err)
+	}
+	return nil
+}
+
+// Close is a no-op for FileOffsetStore; it exists to satisfy OffsetStore.
+func (s *FileOffsetStore) Close() error {
+	return nil
+}
+
+// KafkaOffsetStore commits checkpoints as messages on a dedicated Kafka
+// topic instead of (or in addition to) a local file, so the last confirmed
+// offset survives the loss of the local disk too. Keying every checkpoint
+// message by the monitored file path means the topic can be compacted down
+// to one record per file.
+type KafkaOffsetStore struct {
+	sink Sink
+	key  string
+}
+
+// NewKafkaOffsetStore creates a KafkaOffsetStore that commits checkpoints
+// for key (typically the monitored file's absolute path) through sink.
+func NewKafkaOffsetStore(sink Sink, key string) *KafkaOffsetStore {
+	return &KafkaOffsetStore{sink: sink, key: key}
+}
+
+// Load is unimplemented for KafkaOffsetStore: reading back the last
+// committed checkpoint requires consuming the topic, which this tool does
+// not otherwise need a consumer for. Callers that configure a Kafka offset
+// store alongside a file store should rely on the file store for Load.
+func (s *KafkaOffsetStore) Load() (*Checkpoint, error) {
+	return nil, fmt.Errorf("KafkaOffsetStore does not support Load; pair it with a FileOffsetStore")
+}
+
+// Save publishes cp to the checkpoint topic. Checkpointing is best-effort;
+// the local file store remains the source of truth for resuming.
+func (s *KafkaOffsetStore) Save(cp Checkpoint) error {
+	data, err := json.Marshal(cp)
+	if err != nil {
+		return fmt.Errorf("failed to marshal checkpoint: %v", err)
+	}
+	return s.sink.Send(context.Background(), []byte(s.key), data, nil)
+}
+
+// Close is a no-op for KafkaOffsetStore; the underlying sink is owned and
+// closed by the caller.
+func (s *KafkaOffsetStore) Close() error {
+	return nil
+}
+
+// CompositeOffsetStore pairs a primary OffsetStore, which remains the
+// source of truth for Load, with a secondary OffsetStore that is written to
+// best-effort. It exists so a KafkaOffsetStore can be layered on top of a
+// FileOffsetStore: file reads stay authoritative, and a secondary-store
+// write failure only logs rather than blocking the checkpoint that matters.
+type CompositeOffsetStore struct {
+	primary   OffsetStore
+	secondary OffsetStore
+}
+
+// NewCompositeOffsetStore creates a CompositeOffsetStore that loads from
+// primary and saves to both primary and secondary.
+func NewCompositeOffsetStore(primary, secondary OffsetStore) *CompositeOffsetStore {
+	return &CompositeOffsetStore{primary: primary, secondary: secondary}
+}
+
+// Load reads the checkpoint from the primary store.
+func (s *CompositeOffsetStore) Load() (*Checkpoint, error) {
+	return s.primary.Load()
+}
+
+// Save persists cp to the primary store, then best-effort to the secondary
+// store; a secondary failure is logged rather than returned, since the
+// primary store is what resume relies on.
+func (s *CompositeOffsetStore) Save(cp Checkpoint) error {
+	if err := s.primary.Save(cp); err != nil {
+		return err
+	}
+	if err := s.secondary.Save(cp); err != nil {
+		log.Printf("Warning: failed to save checkpoint to secondary store: %v", err)
+	}
+	return nil
+}
+
+// Close closes both the primary and secondary stores.
+func (s *CompositeOffsetStore) Close() error {
+	if err := s.secondary.Close(); err != nil {
+		log.Printf("Warning: failed to close secondary checkpoint store: %v", err)
+	}
+	return s.primary.Close()
+}
+
+// pendingCheckpoint is a Checkpoint that can only be applied once every
+// QueuedRecord up to seq has been confirmed sent.
+type pendingCheckpoint struct {
+	seq uint64
+	cp  Checkpoint
+}
+
+// CheckpointTracker holds back a Checkpoint until the sink has actually
+// confirmed every record up to the point it describes, rather than merely
+// having had them enqueued. Without this, a crash while records sit in the
+// batch queue or in flight to the broker would have already checkpointed
+// past them, silently losing them on resume. Enqueue is called once per
+// chunk read from the tailer (the producer side); MarkDone is called by
+// the BatchQueue as records are confirmed (the consumer side). A single
+// mutex guards both so Snapshot is race-free no matter which goroutine
+// calls it.
+type CheckpointTracker struct {
+	mu        sync.Mutex
+	current   Checkpoint
+	confirmed uint64
+	pending   []pendingCheckpoint
+}
+
+// NewCheckpointTracker creates a CheckpointTracker whose Snapshot reports
+// initial until a later chunk's records are confirmed and supersede it.
+func NewCheckpointTracker(initial Checkpoint) *CheckpointTracker {
+	return &CheckpointTracker{current: initial}
+}
+
+// Enqueue records that cp describes the state of the world once every
+// record with Seq <= seq has been confirmed sent. seq may repeat the
+// previously enqueued value (e.g. a chunk that only buffered a partial
+// line produced no new records), in which case cp becomes current as soon
+// as previously enqueued records are.
+func (t *CheckpointTracker) Enqueue(seq uint64, cp Checkpoint) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.pending = append(t.pending, pendingCheckpoint{seq: seq, cp: cp})
+	t.resolve()
+}
+
+// MarkDone reports that every record with Seq <= seq has now been
+// confirmed sent (or deliberately dropped), advancing Snapshot to the
+// furthest pending checkpoint that's now fully covered.
+func (t *CheckpointTracker) MarkDone(seq uint64) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if seq > t.confirmed {
+		t.confirmed = seq
+	}
+	t.resolve()
+}
+
+// resolve must be called with mu held. It applies every pending checkpoint
+// whose seq is now fully confirmed, in order.
+func (t *CheckpointTracker) resolve() {
+	for len(t.pending) > 0 && t.pending[0].seq <= t.confirmed {
+		t.current = t.pending[0].cp
+		t.pending = t.pending[1:]
+	}
+}
+
+// Snapshot returns the most advanced Checkpoint that is fully confirmed,
+// safe to call concurrently with Enqueue and MarkDone.
+func (t *CheckpointTracker) Snapshot() Checkpoint {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.current
+}
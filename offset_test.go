@@ -0,0 +1,179 @@
+package main
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestFileOffsetStoreRoundTrip(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "test.offset")
+	store := NewFileOffsetStore(path)
+
+	if cp, err := store.Load(); err != nil || cp != nil {
+		t.Fatalf("Load() on missing file = %v, %v; want nil, nil", cp, err)
+	}
+
+	want := Checkpoint{
+		Inode:           42,
+		Device:          7,
+		Size:            1024,
+		ModTime:         time.Now().Truncate(time.Second),
+		LastKafkaOffset: 5,
+	}
+	if err := store.Save(want); err != nil {
+		t.Fatalf("Save() error = %v", err)
+	}
+
+	got, err := store.Load()
+	if err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+	if got == nil {
+		t.Fatal("Load() = nil; want the saved checkpoint")
+	}
+	if got.Inode != want.Inode || got.Device != want.Device || got.Size != want.Size ||
+		got.LastKafkaOffset != want.LastKafkaOffset || !got.ModTime.Equal(want.ModTime) {
+		t.Fatalf("Load() = %+v; want %+v", got, want)
+	}
+}
+
+func TestFileOffsetStoreSaveLeavesNoTempFile(t *testing.T) {
+	dir := t.TempDir()
+	store := NewFileOffsetStore(filepath.Join(dir, "test.offset"))
+
+	if err := store.Save(Checkpoint{Size: 1}); err != nil {
+		t.Fatalf("Save() error = %v", err)
+	}
+
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		t.Fatalf("ReadDir() error = %v", err)
+	}
+	for _, e := range entries {
+		if e.Name() != "test.offset" {
+			t.Errorf("unexpected leftover file after Save(): %s", e.Name())
+		}
+	}
+}
+
+type fakeOffsetStore struct {
+	loadCP   *Checkpoint
+	loadErr  error
+	saveErr  error
+	saved    []Checkpoint
+	closed   bool
+	closeErr error
+}
+
+func (f *fakeOffsetStore) Load() (*Checkpoint, error) { return f.loadCP, f.loadErr }
+func (f *fakeOffsetStore) Save(cp Checkpoint) error {
+	f.saved = append(f.saved, cp)
+	return f.saveErr
+}
+func (f *fakeOffsetStore) Close() error {
+	f.closed = true
+	return f.closeErr
+}
+
+func TestCompositeOffsetStoreLoadsFromPrimary(t *testing.T) {
+	primary := &fakeOffsetStore{loadCP: &Checkpoint{Size: 99}}
+	secondary := &fakeOffsetStore{}
+	store := NewCompositeOffsetStore(primary, secondary)
+
+	cp, err := store.Load()
+	if err != nil || cp == nil || cp.Size != 99 {
+		t.Fatalf("Load() = %+v, %v; want primary's checkpoint", cp, err)
+	}
+}
+
+func TestCompositeOffsetStoreSaveWritesBothAndToleratesSecondaryFailure(t *testing.T) {
+	primary := &fakeOffsetStore{}
+	secondary := &fakeOffsetStore{saveErr: errors.New("kafka unavailable")}
+	store := NewCompositeOffsetStore(primary, secondary)
+
+	cp := Checkpoint{Size: 7}
+	if err := store.Save(cp); err != nil {
+		t.Fatalf("Save() error = %v; a secondary failure should not fail Save", err)
+	}
+	if len(primary.saved) != 1 || primary.saved[0] != cp {
+		t.Fatalf("primary.saved = %+v; want [%+v]", primary.saved, cp)
+	}
+	if len(secondary.saved) != 1 {
+		t.Fatalf("secondary.saved = %+v; want one attempt even though it failed", secondary.saved)
+	}
+}
+
+func TestCompositeOffsetStoreSaveFailsWhenPrimaryFails(t *testing.T) {
+	primary := &fakeOffsetStore{saveErr: errors.New("disk full")}
+	secondary := &fakeOffsetStore{}
+	store := NewCompositeOffsetStore(primary, secondary)
+
+	if err := store.Save(Checkpoint{}); err == nil {
+		t.Fatal("Save() error = nil; want error when primary fails")
+	}
+	if len(secondary.saved) != 0 {
+		t.Fatalf("secondary.saved = %+v; want no attempt when primary fails", secondary.saved)
+	}
+}
+
+func TestCheckpointTrackerSnapshotStartsAtInitial(t *testing.T) {
+	initial := Checkpoint{Size: 1}
+	tracker := NewCheckpointTracker(initial)
+
+	if got := tracker.Snapshot(); got != initial {
+		t.Fatalf("Snapshot() = %+v; want initial %+v", got, initial)
+	}
+}
+
+func TestCheckpointTrackerHoldsBackUntilConfirmed(t *testing.T) {
+	initial := Checkpoint{Size: 0}
+	tracker := NewCheckpointTracker(initial)
+
+	cp1 := Checkpoint{Size: 10}
+	cp2 := Checkpoint{Size: 20}
+	tracker.Enqueue(5, cp1)
+	tracker.Enqueue(8, cp2)
+
+	if got := tracker.Snapshot(); got != initial {
+		t.Fatalf("Snapshot() = %+v; want still initial before anything is confirmed", got)
+	}
+
+	tracker.MarkDone(5)
+	if got := tracker.Snapshot(); got != cp1 {
+		t.Fatalf("Snapshot() = %+v; want %+v once seq 5 is confirmed", got, cp1)
+	}
+
+	tracker.MarkDone(8)
+	if got := tracker.Snapshot(); got != cp2 {
+		t.Fatalf("Snapshot() = %+v; want %+v once seq 8 is confirmed", got, cp2)
+	}
+}
+
+func TestCheckpointTrackerMarkDoneOutOfOrderSkipsToFurthestCovered(t *testing.T) {
+	tracker := NewCheckpointTracker(Checkpoint{})
+
+	cp1 := Checkpoint{Size: 10}
+	cp2 := Checkpoint{Size: 20}
+	tracker.Enqueue(5, cp1)
+	tracker.Enqueue(8, cp2)
+
+	tracker.MarkDone(8)
+	if got := tracker.Snapshot(); got != cp2 {
+		t.Fatalf("Snapshot() = %+v; want %+v, confirming seq 8 should also resolve seq 5's checkpoint", got, cp2)
+	}
+}
+
+func TestCheckpointTrackerRepeatedSeqResolvesImmediately(t *testing.T) {
+	tracker := NewCheckpointTracker(Checkpoint{})
+
+	tracker.MarkDone(3)
+	cp := Checkpoint{Size: 30}
+	tracker.Enqueue(3, cp)
+
+	if got := tracker.Snapshot(); got != cp {
+		t.Fatalf("Snapshot() = %+v; want %+v, since seq 3 was already confirmed before Enqueue", got, cp)
+	}
+}
@@ -0,0 +1,171 @@
+package main
+
+import (
+	"context"
+	"log"
+	"sync/atomic"
+	"time"
+)
+
+// QueuedRecord is a single encoded log record waiting to be batched and
+// sent through a Sink. Seq is a caller-assigned, strictly increasing
+// sequence number identifying the record; it lets a caller (see
+// CheckpointTracker) learn which of its own records have actually been
+// sent once onDone reports them back.
+type QueuedRecord struct {
+	Seq   uint64
+	Key   []byte
+	Value []byte
+}
+
+// BatchQueue sits between the tailer and the Sink. It buffers
+// QueuedRecords and drains them in size- and time-bounded groups, sending
+// each record as its own Sink message so one Kafka message still maps to
+// one log line; grouping only paces how often the sink is driven. It
+// bounds memory use: once QueueSize records are buffered, Enqueue either
+// blocks (giving the tailer backpressure) or drops the record and counts
+// it, depending on config.DropWhenFull.
+type BatchQueue struct {
+	sink   Sink
+	config *KafkaConfig
+	onDone func(seq uint64)
+
+	records chan QueuedRecord
+
+	depth int64
+	drops int64
+}
+
+// NewBatchQueue creates a BatchQueue that drains into sink using the
+// batching parameters in config. Run must be called (in its own
+// goroutine) to start draining it. onDone, if non-nil, is called with a
+// record's Seq once that record has either been sent successfully or
+// deliberately dropped (DropWhenFull); it is never called for a record
+// that failed to send, since the caller must not treat that record as
+// delivered. onDone is invoked from the Run goroutine for sent records and
+// from Enqueue's caller for dropped ones, so it may be called concurrently.
+func NewBatchQueue(sink Sink, config *KafkaConfig, onDone func(seq uint64)) *BatchQueue {
+	size := config.QueueSize
+	if size <= 0 {
+		size = 1000
+	}
+	return &BatchQueue{
+		sink:    sink,
+		config:  config,
+		onDone:  onDone,
+		records: make(chan QueuedRecord, size),
+	}
+}
+
+// Enqueue adds a record to the queue. When the queue is full it either
+// blocks until space frees up, or drops the record and increments the drop
+// counter, depending on config.DropWhenFull. A dropped record still counts
+// as done: DropWhenFull is an explicit choice to favor throughput over
+// completeness, and treating it otherwise would leave the caller waiting
+// forever on a record that will never be sent.
+func (q *BatchQueue) Enqueue(record QueuedRecord) {
+	if q.config.DropWhenFull {
+		select {
+		case q.records <- record:
+			atomic.AddInt64(&q.depth, 1)
+		default:
+			dropped := atomic.AddInt64(&q.drops, 1)
+			log.Printf("Warning: batch queue full, dropping record (total dropped: %d)", dropped)
+			q.markDone(record.Seq)
+		}
+		return
+	}
+	q.records <- record
+	atomic.AddInt64(&q.depth, 1)
+}
+
+// Depth returns the number of records currently buffered, for exposing as
+// a gauge.
+func (q *BatchQueue) Depth() int64 {
+	return atomic.LoadInt64(&q.depth)
+}
+
+// Drops returns the number of records discarded because the queue was
+// full, for exposing as a counter.
+func (q *BatchQueue) Drops() int64 {
+	return atomic.LoadInt64(&q.drops)
+}
+
+// Run coalesces queued records into batches and publishes them through the
+// sink until Close is called and the queue drains.
+func (q *BatchQueue) Run() {
+	lingerMS := q.config.LingerMS
+	if lingerMS <= 0 {
+		lingerMS = 100
+	}
+	linger := time.Duration(lingerMS) * time.Millisecond
+
+	batchBytes := q.config.BatchBytes
+	if batchBytes <= 0 {
+		batchBytes = 1 << 20 // 1 MiB
+	}
+
+	var batch []QueuedRecord
+	var batchSize int
+	timer := time.NewTimer(linger)
+	defer timer.Stop()
+
+	flush := func() {
+		if len(batch) == 0 {
+			return
+		}
+		q.sendBatch(batch)
+		atomic.AddInt64(&q.depth, -int64(len(batch)))
+		batch = nil
+		batchSize = 0
+	}
+
+	for {
+		select {
+		case record, ok := <-q.records:
+			if !ok {
+				flush()
+				return
+			}
+			batch = append(batch, record)
+			batchSize += len(record.Value)
+			if batchSize >= batchBytes {
+				flush()
+				timer.Reset(linger)
+			}
+		case <-timer.C:
+			flush()
+			timer.Reset(linger)
+		}
+	}
+}
+
+// Close signals Run to flush and stop once the queue drains.
+func (q *BatchQueue) Close() {
+	close(q.records)
+}
+
+// sendBatch publishes each record in batch as its own Sink message,
+// preserving the one-Kafka-message-per-line contract: coalescing only
+// governs how long records wait in the queue before being drained, not how
+// many lines end up in one message. Protocol-level batching and
+// compression are left to the driver's own client (its Compression
+// config), which speaks the wire format downstream consumers expect. A
+// record is only reported done once Send confirms it; a failed record is
+// not, so a caller tracking checkpoints never marks it delivered.
+func (q *BatchQueue) sendBatch(batch []QueuedRecord) {
+	for _, record := range batch {
+		if err := q.sink.Send(context.Background(), record.Key, record.Value, nil); err != nil {
+			log.Printf("Failed to send record to sink: %v", err)
+			continue
+		}
+		q.markDone(record.Seq)
+	}
+}
+
+// markDone reports seq to onDone, if one was configured.
+func (q *BatchQueue) markDone(seq uint64) {
+	if q.onDone != nil {
+		q.onDone(seq)
+	}
+}
@@ -0,0 +1,160 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+)
+
+type fakeSink struct {
+	mu    sync.Mutex
+	sends [][]byte
+	fail  func(value []byte) bool
+}
+
+func (s *fakeSink) Send(ctx context.Context, key, value []byte, headers []Header) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.fail != nil && s.fail(value) {
+		return errors.New("simulated send failure")
+	}
+	s.sends = append(s.sends, append([]byte(nil), value...))
+	return nil
+}
+
+func (s *fakeSink) Close() error { return nil }
+
+func (s *fakeSink) sendCount() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return len(s.sends)
+}
+
+func TestBatchQueueSendsOneMessagePerRecord(t *testing.T) {
+	sink := &fakeSink{}
+	config := &KafkaConfig{LingerMS: 5}
+	q := NewBatchQueue(sink, config, nil)
+	go q.Run()
+
+	lines := []string{"one", "two", "three"}
+	for _, line := range lines {
+		q.Enqueue(QueuedRecord{Key: []byte("k"), Value: []byte(line)})
+	}
+	q.Close()
+
+	deadline := time.After(time.Second)
+	for sink.sendCount() < len(lines) {
+		select {
+		case <-deadline:
+			t.Fatalf("timed out waiting for sends; got %d, want %d", sink.sendCount(), len(lines))
+		case <-time.After(time.Millisecond):
+		}
+	}
+
+	sink.mu.Lock()
+	defer sink.mu.Unlock()
+	if len(sink.sends) != len(lines) {
+		t.Fatalf("sink received %d messages; want %d (one per record, never joined)", len(sink.sends), len(lines))
+	}
+	for i, want := range lines {
+		if string(sink.sends[i]) != want {
+			t.Errorf("sends[%d] = %q; want %q", i, sink.sends[i], want)
+		}
+	}
+}
+
+func TestBatchQueueEnqueueBlocksWhenFullByDefault(t *testing.T) {
+	config := &KafkaConfig{QueueSize: 1}
+	q := NewBatchQueue(&fakeSink{}, config, nil)
+
+	q.records <- QueuedRecord{Value: []byte("fills the buffer")}
+
+	done := make(chan struct{})
+	go func() {
+		q.Enqueue(QueuedRecord{Value: []byte("blocks")})
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		t.Fatal("Enqueue() returned immediately; want it to block while the queue is full")
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	<-q.records
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("Enqueue() never returned after the queue drained")
+	}
+}
+
+func TestBatchQueueDropsWhenFullIfConfigured(t *testing.T) {
+	config := &KafkaConfig{QueueSize: 1, DropWhenFull: true}
+	q := NewBatchQueue(&fakeSink{}, config, nil)
+
+	q.records <- QueuedRecord{Value: []byte("fills the buffer")}
+	q.Enqueue(QueuedRecord{Value: []byte("dropped")})
+
+	if got := q.Drops(); got != 1 {
+		t.Fatalf("Drops() = %d; want 1", got)
+	}
+}
+
+func TestBatchQueueReportsDoneOnSendButNotOnFailure(t *testing.T) {
+	var mu sync.Mutex
+	var done []uint64
+	onDone := func(seq uint64) {
+		mu.Lock()
+		defer mu.Unlock()
+		done = append(done, seq)
+	}
+
+	sink := &fakeSink{fail: func(value []byte) bool { return string(value) == "fails" }}
+	config := &KafkaConfig{LingerMS: 5}
+	q := NewBatchQueue(sink, config, onDone)
+	go q.Run()
+
+	q.Enqueue(QueuedRecord{Seq: 1, Value: []byte("sent")})
+	q.Enqueue(QueuedRecord{Seq: 2, Value: []byte("fails")})
+	q.Close()
+
+	deadline := time.After(time.Second)
+	for sink.sendCount() < 1 {
+		select {
+		case <-deadline:
+			t.Fatalf("timed out waiting for send; got %d, want 1", sink.sendCount())
+		case <-time.After(time.Millisecond):
+		}
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	if len(done) != 1 || done[0] != 1 {
+		t.Fatalf("onDone called with %v; want only seq 1 (seq 2 failed to send and must not be marked done)", done)
+	}
+}
+
+func TestBatchQueueReportsDoneOnDrop(t *testing.T) {
+	var mu sync.Mutex
+	var done []uint64
+	onDone := func(seq uint64) {
+		mu.Lock()
+		defer mu.Unlock()
+		done = append(done, seq)
+	}
+
+	config := &KafkaConfig{QueueSize: 1, DropWhenFull: true}
+	q := NewBatchQueue(&fakeSink{}, config, onDone)
+
+	q.records <- QueuedRecord{Seq: 1, Value: []byte("fills the buffer")}
+	q.Enqueue(QueuedRecord{Seq: 2, Value: []byte("dropped")})
+
+	mu.Lock()
+	defer mu.Unlock()
+	if len(done) != 1 || done[0] != 2 {
+		t.Fatalf("onDone called with %v; want only the dropped seq 2", done)
+	}
+}
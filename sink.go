@@ -0,0 +1,47 @@
+package main
+
+import (
+	"context"
+	"fmt"
+)
+
+// Header is a single key/value pair attached to a published record,
+// mirroring the header support offered by the underlying Kafka client
+// libraries.
+type Header struct {
+	Key   string
+	Value []byte
+}
+
+// Sink abstracts over the underlying message transport so the monitor
+// isn't tied to a specific Kafka client library. In particular it keeps
+// confluent-kafka-go, which requires cgo and librdkafka, an optional
+// choice rather than a hard dependency: the confluent driver only compiles
+// into binaries built with -tags confluent, so users who only want the
+// pure-Go sarama or franzgo drivers don't need cgo or librdkafka to build.
+type Sink interface {
+	// Send publishes value under key, attaching the given headers, and
+	// blocks until the send is confirmed or fails.
+	Send(ctx context.Context, key, value []byte, headers []Header) error
+	// Close flushes any buffered records and releases the sink's
+	// resources.
+	Close() error
+}
+
+// NewSink builds a Sink for config.Driver. Supported drivers are
+// "confluent" (the default, only available in binaries built with -tags
+// confluent), "sarama", "franzgo", and "stdout".
+func NewSink(config *KafkaConfig) (Sink, error) {
+	switch config.Driver {
+	case "", "confluent":
+		return NewConfluentSink(config)
+	case "sarama":
+		return NewSaramaSink(config)
+	case "franzgo":
+		return NewFranzGoSink(config)
+	case "stdout":
+		return NewStdoutSink(config)
+	default:
+		return nil, fmt.Errorf("unknown kafka driver %q", config.Driver)
+	}
+}
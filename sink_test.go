@@ -0,0 +1,76 @@
+package main
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestNewSinkUnknownDriver(t *testing.T) {
+	_, err := NewSink(&KafkaConfig{Driver: "carrier-pigeon"})
+	if err == nil {
+		t.Fatal("NewSink() error = nil; want error for unknown driver")
+	}
+	if !strings.Contains(err.Error(), "carrier-pigeon") {
+		t.Errorf("error = %v; want it to name the unknown driver", err)
+	}
+}
+
+func TestNewSinkDefaultsToConfluent(t *testing.T) {
+	// This binary is built without -tags confluent, so both the empty
+	// driver and "confluent" should hit the stub and fail the same way,
+	// confirming NewSink dispatches them to the confluent driver rather
+	// than silently falling back to something else.
+	for _, driver := range []string{"", "confluent"} {
+		_, err := NewSink(&KafkaConfig{Driver: driver})
+		if err == nil {
+			t.Fatalf("NewSink(Driver: %q) error = nil; want the confluent stub's error", driver)
+		}
+		if !strings.Contains(err.Error(), "confluent") {
+			t.Errorf("NewSink(Driver: %q) error = %v; want it to mention the confluent driver", driver, err)
+		}
+	}
+}
+
+func TestNewSinkStdoutWritesToFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "out.jsonl")
+	sink, err := NewSink(&KafkaConfig{Driver: "stdout", Broker: path})
+	if err != nil {
+		t.Fatalf("NewSink() error = %v", err)
+	}
+	defer sink.Close()
+
+	if err := sink.Send(context.Background(), []byte("k"), []byte(`{"message":"hi"}`), nil); err != nil {
+		t.Fatalf("Send() error = %v", err)
+	}
+
+	got, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("ReadFile(%s) error = %v", path, err)
+	}
+	if want := "{\"message\":\"hi\"}\n"; string(got) != want {
+		t.Errorf("file contents = %q; want %q", got, want)
+	}
+}
+
+func TestNewSinkSaramaRejectsUnknownCompression(t *testing.T) {
+	_, err := NewSink(&KafkaConfig{Driver: "sarama", Compression: "brotli"})
+	if err == nil {
+		t.Fatal("NewSink() error = nil; want error for unknown compression codec")
+	}
+	if !strings.Contains(err.Error(), "brotli") {
+		t.Errorf("error = %v; want it to name the unknown codec", err)
+	}
+}
+
+func TestNewSinkFranzGoRejectsUnknownCompression(t *testing.T) {
+	_, err := NewSink(&KafkaConfig{Driver: "franzgo", Compression: "brotli"})
+	if err == nil {
+		t.Fatal("NewSink() error = nil; want error for unknown compression codec")
+	}
+	if !strings.Contains(err.Error(), "brotli") {
+		t.Errorf("error = %v; want it to name the unknown codec", err)
+	}
+}
@@ -0,0 +1,19 @@
+//go:build !windows
+
+package main
+
+import (
+	"os"
+	"syscall"
+)
+
+// fileIdentity returns the inode and device number backing fi, used to
+// detect log rotation (a new file replacing the one we were tailing) even
+// when the path on disk stays the same.
+func fileIdentity(fi os.FileInfo) (inode, device uint64) {
+	stat, ok := fi.Sys().(*syscall.Stat_t)
+	if !ok {
+		return 0, 0
+	}
+	return uint64(stat.Ino), uint64(stat.Dev)
+}
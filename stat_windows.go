@@ -0,0 +1,13 @@
+//go:build windows
+
+package main
+
+import "os"
+
+// fileIdentity has no cheap equivalent to a unix inode/device pair on
+// Windows via os.FileInfo alone, so it reports 0 for both. Rotation
+// detection there relies only on the Tailer's size check (a file shorter
+// than the read offset is read again from 0).
+func fileIdentity(fi os.FileInfo) (inode, device uint64) {
+	return 0, 0
+}
@@ -0,0 +1,51 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+)
+
+// StdoutSink writes records as newline-delimited JSON to stdout, or to a
+// file when config.Broker names one, instead of a Kafka broker. It exists
+// for local testing and for exercising the rest of the pipeline without a
+// Kafka cluster.
+type StdoutSink struct {
+	out    io.Writer
+	closer io.Closer
+}
+
+// NewStdoutSink creates a Sink that writes to stdout by default, or to the
+// file named by config.Broker if set.
+func NewStdoutSink(config *KafkaConfig) (*StdoutSink, error) {
+	if config.Broker == "" {
+		return &StdoutSink{out: os.Stdout}, nil
+	}
+
+	file, err := os.OpenFile(config.Broker, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open stdout sink file: %v", err)
+	}
+	return &StdoutSink{out: file, closer: file}, nil
+}
+
+// Send writes value, already JSON-encoded, followed by a newline. key and
+// headers are ignored; they exist only to satisfy the Sink interface.
+func (s *StdoutSink) Send(ctx context.Context, key, value []byte, headers []Header) error {
+	if _, err := s.out.Write(value); err != nil {
+		return fmt.Errorf("failed to write to stdout sink: %v", err)
+	}
+	if _, err := s.out.Write([]byte("\n")); err != nil {
+		return fmt.Errorf("failed to write to stdout sink: %v", err)
+	}
+	return nil
+}
+
+// Close releases the underlying file, if one was opened.
+func (s *StdoutSink) Close() error {
+	if s.closer != nil {
+		return s.closer.Close()
+	}
+	return nil
+}
@@ -0,0 +1,243 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"sync/atomic"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+)
+
+// LogChunk is a span of raw bytes read from the tailed file, along with
+// the file size, modtime, and identity observed when it was read, so
+// callers can drive checkpointing without re-stating the file themselves.
+// Inode and Device reflect whatever file is currently open, which may have
+// changed since the Tailer was created if a rotation happened in between.
+type LogChunk struct {
+	Data    []byte
+	Size    int64
+	ModTime time.Time
+	Inode   uint64
+	Device  uint64
+}
+
+// Tailer follows a log file with fsnotify instead of polling, reacting to
+// WRITE, RENAME, REMOVE, and CREATE events on both the file and its parent
+// directory. It handles the two HDFS log rotation patterns in use:
+// rename-then-create (the old file is moved aside and a new one appears at
+// the original path) and copy-truncate (the file is truncated in place).
+// Decoupling I/O from whatever drains Chunks() removes the race in the old
+// stat-polling loop, where a fast writer followed by a rotate could lose
+// the tail between two stat() calls.
+type Tailer struct {
+	path    string
+	watcher *fsnotify.Watcher
+	chunks  chan LogChunk
+	errs    chan error
+
+	file   *os.File
+	size   int64
+	inode  uint64
+	device uint64
+
+	lag int64 // atomic; bytes written but not yet read, mirrors the lagBytes gauge
+}
+
+// NewTailer creates a Tailer for path, resuming from startSize (typically
+// a checkpointed offset).
+func NewTailer(path string, startSize int64) (*Tailer, error) {
+	watcher, err := fsnotify.NewWatcher()
+	if err != nil {
+		return nil, fmt.Errorf("failed to create fsnotify watcher: %v", err)
+	}
+	if err := watcher.Add(filepath.Dir(path)); err != nil {
+		watcher.Close()
+		return nil, fmt.Errorf("failed to watch directory of %s: %v", path, err)
+	}
+	if err := watcher.Add(path); err != nil {
+		watcher.Close()
+		return nil, fmt.Errorf("failed to watch %s: %v", path, err)
+	}
+
+	t := &Tailer{
+		path:    path,
+		watcher: watcher,
+		chunks:  make(chan LogChunk),
+		errs:    make(chan error, 1),
+		size:    startSize,
+	}
+	if err := t.openCurrent(); err != nil {
+		watcher.Close()
+		return nil, err
+	}
+	return t, nil
+}
+
+// openCurrent (re)opens path, recording its identity so future rotations
+// can be detected.
+func (t *Tailer) openCurrent() error {
+	file, err := os.Open(t.path)
+	if err != nil {
+		return fmt.Errorf("failed to open %s: %v", t.path, err)
+	}
+	fileInfo, err := file.Stat()
+	if err != nil {
+		file.Close()
+		return fmt.Errorf("failed to stat %s: %v", t.path, err)
+	}
+	t.file = file
+	t.inode, t.device = fileIdentity(fileInfo)
+	return nil
+}
+
+// Chunks returns the channel new log chunks arrive on. It is closed once
+// Run returns.
+func (t *Tailer) Chunks() <-chan LogChunk {
+	return t.chunks
+}
+
+// Errs returns the channel tailer errors are reported on. Reporting an
+// error does not stop Run.
+func (t *Tailer) Errs() <-chan error {
+	return t.errs
+}
+
+// LagBytes returns the most recently observed number of bytes written to
+// the tailed file that have not yet been read. It stays at 0 in steady
+// state, since each readNew call drains everything it sees; it only stays
+// elevated when a read is failing, which is what makes it useful as a
+// readiness signal.
+func (t *Tailer) LagBytes() int64 {
+	return atomic.LoadInt64(&t.lag)
+}
+
+// Close stops watching the file and releases the underlying file handle.
+func (t *Tailer) Close() error {
+	if t.file != nil {
+		t.file.Close()
+	}
+	return t.watcher.Close()
+}
+
+// Run drives the tailer until stop is closed or the watcher's event
+// channel closes. It should be run in its own goroutine. An initial read
+// is performed before entering the event loop, in case bytes arrived
+// between NewTailer and Run.
+func (t *Tailer) Run(stop <-chan struct{}) {
+	defer close(t.chunks)
+	t.readNew()
+
+	for {
+		select {
+		case <-stop:
+			return
+		case event, ok := <-t.watcher.Events:
+			if !ok {
+				return
+			}
+			t.handleEvent(event)
+		case err, ok := <-t.watcher.Errors:
+			if !ok {
+				return
+			}
+			t.reportErr(err)
+		}
+	}
+}
+
+func (t *Tailer) handleEvent(event fsnotify.Event) {
+	if event.Name != t.path {
+		return
+	}
+
+	switch {
+	case event.Op&fsnotify.Write != 0:
+		t.readNew()
+	case event.Op&(fsnotify.Rename|fsnotify.Remove) != 0:
+		// The old file was moved aside (rename-then-create) or unlinked.
+		// Drain whatever remains in the already-open handle by inode, then
+		// wait for the CREATE event that brings the path back.
+		t.readNew()
+	case event.Op&fsnotify.Create != 0:
+		t.reopen()
+	}
+}
+
+// reopen switches to the freshly created file at t.path after a
+// rename-then-create rotation, resetting the offset to 0.
+func (t *Tailer) reopen() {
+	rotationsTotal.Inc()
+	if t.file != nil {
+		t.file.Close()
+	}
+	if err := t.openCurrent(); err != nil {
+		t.reportErr(err)
+		return
+	}
+	t.size = 0
+	t.readNew()
+}
+
+// readNew reads any bytes appended since the last read, detecting
+// copy-truncate rotation (size shrank under us) and resuming from the
+// start of the file when it has.
+func (t *Tailer) readNew() {
+	fileInfo, err := t.file.Stat()
+	if err != nil {
+		t.reportErr(fmt.Errorf("failed to stat tailed file: %v", err))
+		return
+	}
+
+	currentSize := fileInfo.Size()
+	fileSizeBytes.Set(float64(currentSize))
+	if currentSize < t.size {
+		log.Printf("Detected copy-truncate rotation on %s (size %d < %d); resuming from 0", t.path, currentSize, t.size)
+		rotationsTotal.Inc()
+		t.size = 0
+	}
+	t.setLag(currentSize - t.size)
+	if currentSize == t.size {
+		return
+	}
+
+	if _, err := t.file.Seek(t.size, io.SeekStart); err != nil {
+		t.reportErr(fmt.Errorf("failed to seek tailed file: %v", err))
+		return
+	}
+
+	data := make([]byte, currentSize-t.size)
+	if _, err := io.ReadFull(t.file, data); err != nil {
+		t.reportErr(fmt.Errorf("failed to read tailed file: %v", err))
+		return
+	}
+
+	t.size = currentSize
+	filePositionBytes.Set(float64(currentSize))
+	t.setLag(0)
+	t.chunks <- LogChunk{
+		Data:    data,
+		Size:    currentSize,
+		ModTime: fileInfo.ModTime(),
+		Inode:   t.inode,
+		Device:  t.device,
+	}
+}
+
+// setLag records lag both on the Prometheus gauge and on t.lag, so
+// LagBytes() can report it back to callers like the readiness tracker.
+func (t *Tailer) setLag(lag int64) {
+	lagBytes.Set(float64(lag))
+	atomic.StoreInt64(&t.lag, lag)
+}
+
+func (t *Tailer) reportErr(err error) {
+	select {
+	case t.errs <- err:
+	default:
+		log.Printf("Tailer error (dropped, channel full): %v", err)
+	}
+}
@@ -0,0 +1,157 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func mustWriteFile(t *testing.T, path string, data string) {
+	t.Helper()
+	if err := os.WriteFile(path, []byte(data), 0644); err != nil {
+		t.Fatalf("WriteFile(%s) error = %v", path, err)
+	}
+}
+
+func mustAppend(t *testing.T, path string, data string) {
+	t.Helper()
+	f, err := os.OpenFile(path, os.O_APPEND|os.O_WRONLY, 0644)
+	if err != nil {
+		t.Fatalf("OpenFile(%s) error = %v", path, err)
+	}
+	defer f.Close()
+	if _, err := f.WriteString(data); err != nil {
+		t.Fatalf("append to %s error = %v", path, err)
+	}
+}
+
+func recvChunk(t *testing.T, tailer *Tailer) LogChunk {
+	t.Helper()
+	select {
+	case chunk, ok := <-tailer.Chunks():
+		if !ok {
+			t.Fatal("Chunks() closed before a chunk arrived")
+		}
+		return chunk
+	case <-time.After(5 * time.Second):
+		t.Fatal("timed out waiting for a chunk")
+		return LogChunk{}
+	}
+}
+
+func TestTailerReadsAppendedBytes(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "app.log")
+	mustWriteFile(t, path, "")
+
+	tailer, err := NewTailer(path, 0)
+	if err != nil {
+		t.Fatalf("NewTailer() error = %v", err)
+	}
+	defer tailer.Close()
+
+	stop := make(chan struct{})
+	defer close(stop)
+	go tailer.Run(stop)
+
+	mustAppend(t, path, "line one\n")
+
+	chunk := recvChunk(t, tailer)
+	if string(chunk.Data) != "line one\n" {
+		t.Fatalf("chunk.Data = %q; want %q", chunk.Data, "line one\n")
+	}
+	if chunk.Size != int64(len("line one\n")) {
+		t.Errorf("chunk.Size = %d; want %d", chunk.Size, len("line one\n"))
+	}
+}
+
+func TestTailerResumesFromStartSize(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "app.log")
+	mustWriteFile(t, path, "already read\nnew stuff\n")
+
+	tailer, err := NewTailer(path, int64(len("already read\n")))
+	if err != nil {
+		t.Fatalf("NewTailer() error = %v", err)
+	}
+	defer tailer.Close()
+
+	stop := make(chan struct{})
+	defer close(stop)
+	go tailer.Run(stop)
+
+	chunk := recvChunk(t, tailer)
+	if string(chunk.Data) != "new stuff\n" {
+		t.Fatalf("chunk.Data = %q; want only the bytes after startSize", chunk.Data)
+	}
+}
+
+func TestTailerHandlesRenameThenCreateRotation(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "app.log")
+	mustWriteFile(t, path, "before rotation\n")
+
+	tailer, err := NewTailer(path, 0)
+	if err != nil {
+		t.Fatalf("NewTailer() error = %v", err)
+	}
+	defer tailer.Close()
+
+	stop := make(chan struct{})
+	defer close(stop)
+	go tailer.Run(stop)
+
+	first := recvChunk(t, tailer)
+	if string(first.Data) != "before rotation\n" {
+		t.Fatalf("chunk.Data = %q; want %q", first.Data, "before rotation\n")
+	}
+	oldInode := first.Inode
+
+	if err := os.Rename(path, filepath.Join(dir, "app.log.1")); err != nil {
+		t.Fatalf("Rename() error = %v", err)
+	}
+	mustWriteFile(t, path, "after rotation\n")
+
+	chunk := recvChunk(t, tailer)
+	if string(chunk.Data) != "after rotation\n" {
+		t.Fatalf("chunk.Data = %q; want %q", chunk.Data, "after rotation\n")
+	}
+	if chunk.Inode == oldInode {
+		t.Error("chunk.Inode unchanged after rename-then-create rotation; want the new file's identity")
+	}
+	if chunk.Size != int64(len("after rotation\n")) {
+		t.Errorf("chunk.Size = %d; want offset to reset to the new file's size", chunk.Size)
+	}
+}
+
+func TestTailerHandlesCopyTruncateRotation(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "app.log")
+	mustWriteFile(t, path, "a long line before truncation\n")
+
+	tailer, err := NewTailer(path, 0)
+	if err != nil {
+		t.Fatalf("NewTailer() error = %v", err)
+	}
+	defer tailer.Close()
+
+	stop := make(chan struct{})
+	defer close(stop)
+	go tailer.Run(stop)
+
+	first := recvChunk(t, tailer)
+	if string(first.Data) != "a long line before truncation\n" {
+		t.Fatalf("chunk.Data = %q; want %q", first.Data, "a long line before truncation\n")
+	}
+
+	if err := os.Truncate(path, 0); err != nil {
+		t.Fatalf("Truncate() error = %v", err)
+	}
+	mustAppend(t, path, "short\n")
+
+	chunk := recvChunk(t, tailer)
+	if string(chunk.Data) != "short\n" {
+		t.Fatalf("chunk.Data = %q; want %q (resumed from 0 after copy-truncate)", chunk.Data, "short\n")
+	}
+}